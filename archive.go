@@ -0,0 +1,149 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// archiveHandler streams the directory at ?path= as a .zip or .tar.gz
+// archive, selected via ?format=zip|tgz (zip is the default). Files are
+// streamed directly to the response as they're walked, so memory use
+// stays flat regardless of directory size.
+func archiveHandler(w http.ResponseWriter, r *http.Request) {
+	if !isDiskBacked() {
+		log.Printf("archive: ROOT_DIR is not disk-backed; /archive is unsupported for this store")
+		http.Error(w, "archive download is not supported for this ROOT_DIR", http.StatusNotImplemented)
+		return
+	}
+
+	rel := filepath.Clean(strings.TrimPrefix(r.URL.Query().Get("path"), "/"))
+	if rel == "." {
+		rel = ""
+	}
+	abs := filepath.Join(rootDir, rel)
+	if !isWithin(abs, rootDir) {
+		http.Error(w, "invalid path", http.StatusBadRequest)
+		return
+	}
+	// Resolve symlinks before walking, so a symlinked directory under
+	// rootDir can't be archived out to files outside it.
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil || !isWithin(real, rootDir) {
+		http.NotFound(w, r)
+		return
+	}
+	abs = real
+
+	info, err := os.Stat(abs)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !info.IsDir() {
+		http.Error(w, "not a directory", http.StatusBadRequest)
+		return
+	}
+
+	format := pick(r.URL.Query().Get("format"), "zip", "tgz")
+	name := filepath.Base(abs)
+	if name == "." || name == string(filepath.Separator) {
+		name = "root"
+	}
+
+	switch format {
+	case "tgz":
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".tar.gz"))
+		w.Header().Set("Content-Type", "application/gzip")
+		writeTarGz(w, abs)
+	default:
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".zip"))
+		w.Header().Set("Content-Type", "application/zip")
+		writeZip(w, abs)
+	}
+}
+
+func writeZip(w http.ResponseWriter, abs string) {
+	zw := zip.NewWriter(w)
+	defer zw.Close()
+
+	filepath.WalkDir(abs, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !isWithin(path, rootDir) {
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		relHeader, err := filepath.Rel(abs, path)
+		if err != nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		hdr, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return nil
+		}
+		hdr.Name = filepath.ToSlash(relHeader)
+		hdr.Method = zip.Deflate
+		entry, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil
+		}
+		defer f.Close()
+		io.Copy(entry, f)
+		return nil
+	})
+}
+
+func writeTarGz(w http.ResponseWriter, abs string) {
+	gz := gzip.NewWriter(w)
+	defer gz.Close()
+	tw := tar.NewWriter(gz)
+	defer tw.Close()
+
+	filepath.WalkDir(abs, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !isWithin(path, rootDir) {
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		relHeader, err := filepath.Rel(abs, path)
+		if err != nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return nil
+		}
+		hdr.Name = filepath.ToSlash(relHeader)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil
+		}
+		defer f.Close()
+		io.Copy(tw, f)
+		return nil
+	})
+}
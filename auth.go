@@ -0,0 +1,310 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	authType  string // "none", "basic", "token"
+	authUsers map[string]string
+	authToken string
+)
+
+func loadAuthConfig() {
+	authType = strings.ToLower(getenv("AUTH_TYPE", "none"))
+	authToken = getenv("AUTH_TOKEN", "")
+	authUsers = parseAuthUsers(getenv("AUTH_USERS", ""))
+}
+
+func parseAuthUsers(spec string) map[string]string {
+	users := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		users[parts[0]] = parts[1]
+	}
+	return users
+}
+
+// authMiddleware gates every request behind AUTH_TYPE before it reaches
+// mux, then layers the per-directory .fbrowser.yml ACLs on top for the
+// specific action being attempted.
+func authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := authenticate(r)
+		if !ok {
+			logFailedAttempt(r)
+			challengeAuth(w)
+			return
+		}
+
+		r.ParseForm()
+		rel := filepath.Clean(strings.TrimPrefix(r.Form.Get("path"), "/"))
+		if rel == "." {
+			rel = ""
+		}
+		acl := aclFor(rel)
+		if authType == "basic" && !acl.allowsUser(user) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if authType != "basic" {
+			warnIgnoredUsersACL(rel, acl)
+		}
+		switch r.URL.Path {
+		case "/upload", "/mkdir":
+			if !acl.Upload {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+		case "/delete", "/rename":
+			if !acl.Delete {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func authenticate(r *http.Request) (string, bool) {
+	switch authType {
+	case "basic":
+		user, pass, ok := r.BasicAuth()
+		if !ok {
+			return "", false
+		}
+		hash, known := authUsers[user]
+		if !known {
+			return "", false
+		}
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(pass)) != nil {
+			return "", false
+		}
+		return user, true
+	case "token":
+		got := bearerToken(r)
+		if got == "" {
+			return "", false
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(authToken)) != 1 {
+			return "", false
+		}
+		return "", true
+	default: // "none"
+		return "", true
+	}
+}
+
+func bearerToken(r *http.Request) string {
+	if v := r.URL.Query().Get("token"); v != "" {
+		return v
+	}
+	const prefix = "Bearer "
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, prefix) {
+		return strings.TrimPrefix(h, prefix)
+	}
+	return ""
+}
+
+func challengeAuth(w http.ResponseWriter) {
+	if authType == "basic" {
+		w.Header().Set("WWW-Authenticate", `Basic realm="file-browser"`)
+	}
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+}
+
+var (
+	failedAttemptsMu sync.Mutex
+	lastLoggedAt     = map[string]time.Time{}
+)
+
+const failedAttemptLogInterval = 10 * time.Second
+
+// logFailedAttempt logs at most once per remote address per
+// failedAttemptLogInterval, so a brute-force client can't flood the log.
+func logFailedAttempt(r *http.Request) {
+	ip := r.RemoteAddr
+	failedAttemptsMu.Lock()
+	defer failedAttemptsMu.Unlock()
+	if t, ok := lastLoggedAt[ip]; ok && time.Since(t) < failedAttemptLogInterval {
+		return
+	}
+	lastLoggedAt[ip] = time.Now()
+	log.Printf("auth: failed attempt from %s on %s", ip, r.URL.Path)
+}
+
+// dirACL is the parsed, inherited form of a .fbrowser.yml file.
+type dirACL struct {
+	Upload bool
+	Delete bool
+	Users  []string // empty means "no restriction beyond AUTH_TYPE"
+}
+
+func (a dirACL) allowsUser(user string) bool {
+	if len(a.Users) == 0 {
+		return true
+	}
+	for _, u := range a.Users {
+		if u == user {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	warnedUsersACLMu sync.Mutex
+	warnedUsersACL   = map[string]bool{}
+)
+
+// warnIgnoredUsersACL flags a .fbrowser.yml users: list that can never be
+// enforced under the current AUTH_TYPE: basic auth is the only mode that
+// identifies a specific user, so a users: restriction under token/none
+// auth would otherwise lock everyone out silently. Logged once per
+// directory rather than per-request.
+func warnIgnoredUsersACL(rel string, acl dirACL) {
+	if len(acl.Users) == 0 {
+		return
+	}
+	warnedUsersACLMu.Lock()
+	defer warnedUsersACLMu.Unlock()
+	if warnedUsersACL[rel] {
+		return
+	}
+	warnedUsersACL[rel] = true
+	log.Printf("auth: ignoring users: restriction on %q; AUTH_TYPE=%s does not identify users", rel, authType)
+}
+
+var (
+	aclCacheMu sync.Mutex
+	aclCache   = map[string]parsedACL{}
+)
+
+// aclFor returns the effective ACL for rel, inheriting settings down the
+// directory tree from rootDir: a .fbrowser.yml in a parent directory
+// applies to its children unless a closer one overrides it.
+func aclFor(rel string) dirACL {
+	dir := rel
+	if info, err := store.Stat(rel); err == nil && !info.IsDir() {
+		dir = filepath.Dir(rel)
+		if dir == "." {
+			dir = ""
+		}
+	}
+
+	// Seed from the global ALLOW_* flags so a deployment with no
+	// .fbrowser.yml anywhere still gets the grants those flags promise;
+	// a closer .fbrowser.yml can only narrow them further, never be the
+	// sole source of a grant.
+	acl := dirACL{Upload: allowUpload, Delete: allowDelete}
+	var dirs []string
+	for d := dir; ; {
+		dirs = append([]string{d}, dirs...)
+		if d == "" {
+			break
+		}
+		parent := filepath.Dir(d)
+		if parent == "." {
+			parent = ""
+		}
+		d = parent
+	}
+	for _, d := range dirs {
+		if a, ok := loadDirACL(d); ok {
+			if a.explicitUpload {
+				acl.Upload = a.Upload && allowUpload
+			}
+			if a.explicitDelete {
+				acl.Delete = a.Delete && allowDelete
+			}
+			if len(a.Users) > 0 {
+				acl.Users = a.Users
+			}
+		}
+	}
+	return acl
+}
+
+type parsedACL struct {
+	dirACL
+	explicitUpload bool
+	explicitDelete bool
+}
+
+func loadDirACL(dir string) (parsedACL, bool) {
+	aclCacheMu.Lock()
+	if cached, ok := aclCache[dir]; ok {
+		aclCacheMu.Unlock()
+		return cached, true
+	}
+	aclCacheMu.Unlock()
+
+	abs := filepath.Join(rootDir, dir, ".fbrowser.yml")
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return parsedACL{}, false
+	}
+	parsed := parseFbrowserYML(data)
+
+	aclCacheMu.Lock()
+	aclCache[dir] = parsed
+	aclCacheMu.Unlock()
+	return parsed, true
+}
+
+// parseFbrowserYML understands the narrow subset of YAML .fbrowser.yml
+// actually uses: top-level "key: value" scalars and "key:" followed by
+// "- item" list entries. It is not a general YAML parser.
+func parseFbrowserYML(data []byte) parsedACL {
+	var out parsedACL
+	lines := strings.Split(string(data), "\n")
+	var currentList *[]string
+	for _, line := range lines {
+		trimmed := strings.TrimRight(line, "\r")
+		if strings.HasPrefix(strings.TrimSpace(trimmed), "- ") {
+			if currentList != nil {
+				*currentList = append(*currentList, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(trimmed), "- ")))
+			}
+			continue
+		}
+		currentList = nil
+		key, val, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		val = strings.TrimSpace(val)
+		switch key {
+		case "upload":
+			out.explicitUpload = true
+			out.Upload = val == "true"
+		case "delete":
+			out.explicitDelete = true
+			out.Delete = val == "true"
+		case "users":
+			if val != "" {
+				out.Users = strings.Split(val, ",")
+			} else {
+				currentList = &out.Users
+			}
+		}
+	}
+	return out
+}
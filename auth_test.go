@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestACLInheritsGlobalFlagsAndOverridesDeeper(t *testing.T) {
+	dir := t.TempDir()
+	must(t, os.MkdirAll(filepath.Join(dir, "restricted", "sub"), 0o755))
+	must(t, os.WriteFile(filepath.Join(dir, "restricted", ".fbrowser.yml"),
+		[]byte("delete: false\nusers:\n  - alice\n"), 0o644))
+
+	origRoot, origStore := rootDir, store
+	origUpload, origDelete := allowUpload, allowDelete
+	origCache := aclCache
+	defer func() {
+		rootDir, store = origRoot, origStore
+		allowUpload, allowDelete = origUpload, origDelete
+		aclCache = origCache
+	}()
+
+	rootDir = dir
+	store = newDiskStore(dir)
+	allowUpload = true
+	allowDelete = true
+	aclCache = map[string]parsedACL{}
+
+	if acl := aclFor(""); !acl.Upload || !acl.Delete {
+		t.Fatalf("root ACL should grant from global ALLOW_* flags with no .fbrowser.yml, got %+v", acl)
+	}
+
+	acl := aclFor("restricted")
+	if !acl.Upload {
+		t.Fatalf("restricted/.fbrowser.yml doesn't set upload, so the global grant should still apply, got %+v", acl)
+	}
+	if acl.Delete {
+		t.Fatalf("restricted/.fbrowser.yml sets delete: false, it should override the global grant, got %+v", acl)
+	}
+	if !acl.allowsUser("alice") {
+		t.Fatal("alice is listed in restricted/.fbrowser.yml users:, should be allowed")
+	}
+	if acl.allowsUser("bob") {
+		t.Fatal("bob is not listed in restricted/.fbrowser.yml users:, should be denied")
+	}
+
+	if acl := aclFor("restricted/sub"); acl.Delete {
+		t.Fatalf("restricted/sub should inherit delete: false from its parent, got %+v", acl)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownloadHandlerRangeAndETag(t *testing.T) {
+	dir := t.TempDir()
+	must(t, os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello world"), 0o644))
+
+	origRoot, origStore := rootDir, store
+	defer func() { rootDir, store = origRoot, origStore }()
+	rootDir = dir
+	store = newDiskStore(dir)
+
+	rec := httptest.NewRecorder()
+	downloadHandler(rec, httptest.NewRequest(http.MethodGet, "/download?path=hello.txt", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("want 200 for a plain download, got %d", rec.Code)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("want an ETag header on the response")
+	}
+
+	rangeReq := httptest.NewRequest(http.MethodGet, "/download?path=hello.txt", nil)
+	rangeReq.Header.Set("Range", "bytes=0-4")
+	rangeRec := httptest.NewRecorder()
+	downloadHandler(rangeRec, rangeReq)
+	if rangeRec.Code != http.StatusPartialContent {
+		t.Fatalf("want 206 for a ranged request, got %d", rangeRec.Code)
+	}
+	if got := rangeRec.Body.String(); got != "hello" {
+		t.Fatalf("want partial body %q, got %q", "hello", got)
+	}
+
+	condReq := httptest.NewRequest(http.MethodGet, "/download?path=hello.txt", nil)
+	condReq.Header.Set("If-None-Match", etag)
+	condRec := httptest.NewRecorder()
+	downloadHandler(condRec, condReq)
+	if condRec.Code != http.StatusNotModified {
+		t.Fatalf("want 304 for a conditional request matching ETag, got %d", condRec.Code)
+	}
+}
@@ -0,0 +1,12 @@
+package main
+
+import "testing"
+
+// must fails the test immediately if err is non-nil, for the repetitive
+// fixture setup (tempdirs, files, symlinks) shared across *_test.go files.
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -4,12 +4,14 @@ import (
 	"embed"
 	"fmt"
 	"html/template"
+	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -24,6 +26,7 @@ var (
 	certFile string
 	keyFile  string
 	tpl      *template.Template
+	store    Store
 )
 
 type fileRow struct {
@@ -46,6 +49,9 @@ type pageData struct {
 	Sort        string
 	Order       string
 	Breadcrumbs []crumb
+	AllowUpload bool
+	AllowDelete bool
+	AllowRename bool
 }
 
 func main() {
@@ -54,9 +60,23 @@ func main() {
 	useTLS = strings.ToLower(getenv("USE_TLS", "false")) == "true"
 	certFile = getenv("CERT_FILE", "certs/cert.pem")
 	keyFile = getenv("KEY_FILE", "certs/key.pem")
+	allowUpload = strings.ToLower(getenv("ALLOW_UPLOAD", "false")) == "true"
+	allowDelete = strings.ToLower(getenv("ALLOW_DELETE", "false")) == "true"
+	allowRename = strings.ToLower(getenv("ALLOW_RENAME", "false")) == "true"
+	loadAuthConfig()
 
-	if err := os.MkdirAll(rootDir, 0o755); err != nil {
-		log.Fatalf("failed to ensure ROOT_DIR: %v", err)
+	indexInterval, err := time.ParseDuration(getenv("INDEX_INTERVAL", "10m"))
+	if err != nil {
+		log.Fatalf("invalid INDEX_INTERVAL: %v", err)
+	}
+	store = newStoreFor(rootDir)
+
+	skipHidden := strings.ToLower(getenv("INDEX_SKIP_HIDDEN", "true")) == "true"
+	searchIndex = newSearchIndex(indexInterval, skipHidden)
+	searchIndex.start()
+
+	if n, err := strconv.ParseInt(getenv("MAX_PREVIEW_BYTES", ""), 10, 64); err == nil && n > 0 {
+		maxPreviewBytes = n
 	}
 
 	funcs := template.FuncMap{
@@ -75,7 +95,6 @@ func main() {
 			return fmt.Sprintf("%.2f %s", f, u[i])
 		},
 	}
-	var err error
 	tpl, err = template.New("").Funcs(funcs).ParseFS(content, "templates/*.html")
 	if err != nil {
 		log.Fatal(err)
@@ -84,16 +103,25 @@ func main() {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", listHandler)
 	mux.HandleFunc("/download", downloadHandler)
+	mux.HandleFunc("/archive", archiveHandler)
+	mux.HandleFunc("/upload", uploadHandler)
+	mux.HandleFunc("/mkdir", mkdirHandler)
+	mux.HandleFunc("/rename", renameHandler)
+	mux.HandleFunc("/delete", deleteHandler)
+	mux.HandleFunc("/search", searchHandler)
+	mux.HandleFunc("/preview", previewHandler)
 	// Serve embedded static at /static/
 	mux.Handle("/static/", http.FileServer(http.FS(content)))
 
-	log.Printf("File Browser serving %s on %s (TLS=%v)", rootDir, addr, useTLS)
+	handler := authMiddleware(mux)
+
+	log.Printf("File Browser serving %s on %s (TLS=%v, auth=%s)", rootDir, addr, useTLS, authType)
 	if useTLS {
 		mustExist(certFile, "certificate")
 		mustExist(keyFile, "private key")
-		log.Fatal(http.ListenAndServeTLS(addr, certFile, keyFile, mux))
+		log.Fatal(http.ListenAndServeTLS(addr, certFile, keyFile, handler))
 	} else {
-		log.Fatal(http.ListenAndServe(addr, mux))
+		log.Fatal(http.ListenAndServe(addr, handler))
 	}
 }
 
@@ -106,13 +134,8 @@ func listHandler(w http.ResponseWriter, r *http.Request) {
 	if rel == "." {
 		rel = ""
 	}
-	abs := filepath.Join(rootDir, rel)
-	if !isWithin(abs, rootDir) {
-		http.Error(w, "invalid path", http.StatusBadRequest)
-		return
-	}
 
-	entries, err := os.ReadDir(abs)
+	entries, err := store.ReadDir(rel)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -168,6 +191,9 @@ func listHandler(w http.ResponseWriter, r *http.Request) {
 		Sort:        sortBy,
 		Order:       order,
 		Breadcrumbs: crumbsFor(rel),
+		AllowUpload: allowUpload,
+		AllowDelete: allowDelete,
+		AllowRename: allowRename,
 	}
 	if err := tpl.ExecuteTemplate(w, "index.html", data); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -176,12 +202,10 @@ func listHandler(w http.ResponseWriter, r *http.Request) {
 
 func downloadHandler(w http.ResponseWriter, r *http.Request) {
 	rel := filepath.Clean(strings.TrimPrefix(r.URL.Query().Get("path"), "/"))
-	abs := filepath.Join(rootDir, rel)
-	if !isWithin(abs, rootDir) {
-		http.Error(w, "invalid path", http.StatusBadRequest)
-		return
+	if rel == "." {
+		rel = ""
 	}
-	info, err := os.Stat(abs)
+	info, err := store.Stat(rel)
 	if err != nil {
 		http.NotFound(w, r)
 		return
@@ -190,8 +214,34 @@ func downloadHandler(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, "/?"+url.Values{"path": {rel}}.Encode(), http.StatusSeeOther)
 		return
 	}
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(abs)))
-	http.ServeFile(w, r, abs)
+
+	f, err := store.Open(rel)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	if r.URL.Query().Get("inline") != "1" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", info.Name()))
+	}
+	w.Header().Set("ETag", etagFor(info))
+
+	// Stores that can't seek (e.g. a zipStore entry) fall back to a plain
+	// copy: no range/conditional support, but still correct.
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		io.Copy(w, f)
+		return
+	}
+	http.ServeContent(w, r, info.Name(), info.ModTime(), rs)
+}
+
+// etagFor computes a cheap, stable ETag from a file's size and
+// modification time, avoiding a full content hash for potentially large
+// files.
+func etagFor(info os.FileInfo) string {
+	return fmt.Sprintf("%q", fmt.Sprintf("%d:%d", info.Size(), info.ModTime().UnixNano()))
 }
 
 func crumbsFor(rel string) []crumb {
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	allowUpload bool
+	allowDelete bool
+	allowRename bool
+)
+
+// jsonError writes a JSON-shaped error for XHR callers, matching the
+// {"error": "..."} convention used across the mutating endpoints.
+func jsonError(w http.ResponseWriter, msg string, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// resolveUnder joins rootRel onto rootDir and resolves it to its real,
+// symlink-free path, re-checking containment against rootDir afterward.
+// Resolving only the final component (e.g. via Lstat) isn't enough: a
+// symlinked directory anywhere in the path lets the rest of it escape
+// rootDir even though the leaf itself is a plain file.
+func resolveUnder(rootRel string) (string, bool) {
+	rel := filepath.Clean(strings.TrimPrefix(rootRel, "/"))
+	if rel == "." {
+		rel = ""
+	}
+	abs := filepath.Join(rootDir, rel)
+	if !isWithin(abs, rootDir) {
+		return "", false
+	}
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil || !isWithin(real, rootDir) {
+		return "", false
+	}
+	return real, true
+}
+
+func uploadHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowUpload {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	dirAbs, ok := resolveUnder(r.URL.Query().Get("path"))
+	if !ok {
+		jsonError(w, "invalid path", http.StatusBadRequest)
+		return
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var saved []string
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if part.FormName() != "file" || part.FileName() == "" {
+			continue
+		}
+		name := filepath.Base(part.FileName())
+		dest := filepath.Join(dirAbs, name)
+		if !isWithin(dest, rootDir) {
+			jsonError(w, "invalid filename", http.StatusBadRequest)
+			return
+		}
+		if err := saveUpload(dirAbs, dest, part); err != nil {
+			jsonError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		saved = append(saved, name)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"saved": saved})
+}
+
+// saveUpload streams src to a temp file in dir and atomically renames it
+// into place once fully written, so a failed/aborted upload never leaves
+// a partial file at dest.
+func saveUpload(dir, dest string, src io.Reader) error {
+	tmp, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, dest); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
+
+func mkdirHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowUpload {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	parentAbs, ok := resolveUnder(r.Form.Get("path"))
+	if !ok {
+		jsonError(w, "invalid path", http.StatusBadRequest)
+		return
+	}
+	name := filepath.Base(r.Form.Get("name"))
+	if name == "" || name == "." || name == string(filepath.Separator) {
+		jsonError(w, "invalid name", http.StatusBadRequest)
+		return
+	}
+	dest := filepath.Join(parentAbs, name)
+	if !isWithin(dest, rootDir) {
+		jsonError(w, "invalid name", http.StatusBadRequest)
+		return
+	}
+	if err := os.Mkdir(dest, 0o755); err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	redirectBack(w, r, r.Form.Get("path"))
+}
+
+func renameHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowRename {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	srcAbs, ok := resolveUnder(r.Form.Get("path"))
+	if !ok {
+		jsonError(w, "invalid source path", http.StatusBadRequest)
+		return
+	}
+	newName := filepath.Base(r.Form.Get("name"))
+	if newName == "" {
+		jsonError(w, "invalid name", http.StatusBadRequest)
+		return
+	}
+	destAbs := filepath.Join(filepath.Dir(srcAbs), newName)
+	if !isWithin(destAbs, rootDir) {
+		jsonError(w, "invalid name", http.StatusBadRequest)
+		return
+	}
+	if err := os.Rename(srcAbs, destAbs); err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	redirectBack(w, r, filepath.ToSlash(filepath.Dir(r.Form.Get("path"))))
+}
+
+func deleteHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowDelete {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	targetAbs, ok := resolveUnder(r.Form.Get("path"))
+	if !ok || targetAbs == filepath.Clean(rootDir) {
+		jsonError(w, "invalid path", http.StatusBadRequest)
+		return
+	}
+	if err := os.RemoveAll(targetAbs); err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	redirectBack(w, r, filepath.ToSlash(filepath.Dir(r.Form.Get("path"))))
+}
+
+// redirectBack sends XHR callers a JSON ok response and browser form
+// posts back to the listing for the given scope.
+func redirectBack(w http.ResponseWriter, r *http.Request, scope string) {
+	if r.Header.Get("X-Requested-With") == "XMLHttpRequest" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"ok": true})
+		return
+	}
+	if scope == "." {
+		scope = ""
+	}
+	http.Redirect(w, r, "/?"+url.Values{"path": {scope}}.Encode(), http.StatusSeeOther)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveUnderRejectsSymlinkEscape(t *testing.T) {
+	dir := t.TempDir()
+	secret := t.TempDir()
+	must(t, os.WriteFile(filepath.Join(secret, "passwd.txt"), []byte("hunter2"), 0o644))
+	must(t, os.Symlink(secret, filepath.Join(dir, "evillink")))
+
+	origRoot := rootDir
+	defer func() { rootDir = origRoot }()
+	rootDir = dir
+
+	if _, ok := resolveUnder("evillink/passwd.txt"); ok {
+		t.Fatal("resolveUnder allowed a path through a symlinked directory to escape rootDir")
+	}
+	if _, ok := resolveUnder("evillink"); ok {
+		t.Fatal("resolveUnder allowed the symlinked directory itself to resolve")
+	}
+}
+
+func TestResolveUnderAllowsPlainPath(t *testing.T) {
+	dir := t.TempDir()
+	must(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o755))
+
+	origRoot := rootDir
+	defer func() { rootDir = origRoot }()
+	rootDir = dir
+
+	abs, ok := resolveUnder("sub")
+	if !ok {
+		t.Fatal("resolveUnder rejected a plain path inside rootDir")
+	}
+	want, err := filepath.EvalSymlinks(filepath.Join(dir, "sub"))
+	must(t, err)
+	if abs != want {
+		t.Fatalf("want %q, got %q", want, abs)
+	}
+}
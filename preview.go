@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/yuin/goldmark"
+)
+
+var maxPreviewBytes int64 = 2 * 1024 * 1024
+
+var markdownExts = map[string]bool{".md": true, ".markdown": true}
+
+// previewHandler renders an inline HTML preview for a file, picking a
+// strategy from its extension and sniffed content type. Anything over
+// maxPreviewBytes, or a type we don't know how to render, falls back to
+// a plain download link instead of being read into memory.
+func previewHandler(w http.ResponseWriter, r *http.Request) {
+	rel := filepath.Clean(strings.TrimPrefix(r.URL.Query().Get("path"), "/"))
+	if rel == "." {
+		rel = ""
+	}
+	info, err := store.Stat(rel)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if info.IsDir() {
+		http.Redirect(w, r, "/?"+url.Values{"path": {rel}}.Encode(), http.StatusSeeOther)
+		return
+	}
+
+	downloadLink := "/download?" + url.Values{"path": {rel}, "inline": {"1"}}.Encode()
+	if info.Size() > maxPreviewBytes {
+		renderFallback(w, info.Name(), downloadLink, "file is larger than the preview size cap")
+		return
+	}
+
+	f, err := store.Open(rel)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	ext := strings.ToLower(filepath.Ext(info.Name()))
+
+	switch {
+	case ext == ".pdf":
+		renderEmbed(w, info.Name(), downloadLink, "iframe")
+		return
+	}
+
+	br := bufio.NewReader(f)
+	sniff, _ := br.Peek(512)
+	contentType := http.DetectContentType(sniff)
+
+	switch {
+	case strings.HasPrefix(contentType, "image/"):
+		renderEmbed(w, info.Name(), downloadLink, "img")
+	case contentType == "application/pdf":
+		renderEmbed(w, info.Name(), downloadLink, "iframe")
+	case markdownExts[ext]:
+		body, err := io.ReadAll(br)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		renderMarkdown(w, info.Name(), downloadLink, body)
+	case strings.HasPrefix(contentType, "text/") || contentType == "application/octet-stream" && looksLikeText(sniff):
+		body, err := io.ReadAll(br)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		renderText(w, info.Name(), downloadLink, string(body))
+	default:
+		renderFallback(w, info.Name(), downloadLink, "no preview available for this file type")
+	}
+}
+
+// looksLikeText is a cheap heuristic for samples that DetectContentType
+// calls application/octet-stream because they hold control bytes: any
+// sample without a NUL byte is treated as text.
+func looksLikeText(sample []byte) bool {
+	for _, b := range sample {
+		if b == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+const previewPageTmpl = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Title}}</title>
+<link rel="stylesheet" href="/static/style.css"></head>
+<body>
+<p><a href="{{.DownloadLink}}">Download</a></p>
+{{.Body}}
+</body></html>`
+
+var previewTpl = template.Must(template.New("preview").Parse(previewPageTmpl))
+
+type previewPage struct {
+	Title        string
+	DownloadLink string
+	Body         template.HTML
+}
+
+// renderText syntax-highlights body with chroma, picking a lexer from
+// name's extension (falling back to plain text if none matches).
+func renderText(w http.ResponseWriter, name, downloadLink, body string) {
+	lexer := lexers.Match(name)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	style := styles.Get("github")
+	if style == nil {
+		style = styles.Fallback
+	}
+	formatter := chromahtml.New(chromahtml.WithClasses(true), chromahtml.Standalone(false))
+
+	var code strings.Builder
+	iterator, err := lexer.Tokenise(nil, body)
+	if err != nil || formatter.Format(&code, style, iterator) != nil {
+		code.Reset()
+		fmt.Fprintf(&code, "<pre class=\"preview-text\">%s</pre>", template.HTMLEscapeString(body))
+	}
+
+	previewTpl.Execute(w, previewPage{
+		Title:        name,
+		DownloadLink: downloadLink,
+		Body:         template.HTML(code.String()),
+	})
+}
+
+// renderMarkdown renders body as CommonMark via goldmark.
+func renderMarkdown(w http.ResponseWriter, name, downloadLink string, body []byte) {
+	var out strings.Builder
+	if err := goldmark.Convert(body, &out); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	previewTpl.Execute(w, previewPage{
+		Title:        name,
+		DownloadLink: downloadLink,
+		Body:         template.HTML(out.String()),
+	})
+}
+
+func renderEmbed(w http.ResponseWriter, name, downloadLink, tag string) {
+	var body string
+	switch tag {
+	case "img":
+		body = fmt.Sprintf(`<img src="%s" alt="%s">`, downloadLink, template.HTMLEscapeString(name))
+	case "iframe":
+		body = fmt.Sprintf(`<iframe src="%s" width="100%%" height="800"></iframe>`, downloadLink)
+	}
+	previewTpl.Execute(w, previewPage{
+		Title:        name,
+		DownloadLink: downloadLink,
+		Body:         template.HTML(body),
+	})
+}
+
+func renderFallback(w http.ResponseWriter, name, downloadLink, reason string) {
+	body := fmt.Sprintf("<p>%s.</p>", template.HTMLEscapeString(reason))
+	previewTpl.Execute(w, previewPage{
+		Title:        name,
+		DownloadLink: downloadLink,
+		Body:         template.HTML(body),
+	})
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"io/fs"
+	"log"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IndexFileItem is a single entry in the recursive search index.
+type IndexFileItem struct {
+	Path string // relative to rootDir, slash-separated
+	Info fs.FileInfo
+}
+
+// SearchIndex periodically walks rootDir and keeps an in-memory snapshot
+// for /search, so recursive lookups don't re-walk the tree on every
+// request. Rebuilds are serialized so a slow walk can't overlap itself.
+type SearchIndex struct {
+	mu       sync.RWMutex
+	items    []IndexFileItem
+	interval time.Duration
+	skipHide bool
+
+	rebuilding sync.Mutex
+}
+
+func newSearchIndex(interval time.Duration, skipHidden bool) *SearchIndex {
+	return &SearchIndex{interval: interval, skipHide: skipHidden}
+}
+
+func (si *SearchIndex) start() {
+	if !isDiskBacked() {
+		log.Printf("search: ROOT_DIR is not disk-backed; the recursive index will stay empty and /search will return no results")
+		return
+	}
+	si.rebuild()
+	go func() {
+		ticker := time.NewTicker(si.interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			si.rebuild()
+		}
+	}()
+}
+
+func (si *SearchIndex) rebuild() {
+	if !si.rebuilding.TryLock() {
+		return // a rebuild is already in flight; skip this tick
+	}
+	defer si.rebuilding.Unlock()
+
+	items := make([]IndexFileItem, 0, 1024)
+	filepath.WalkDir(rootDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if path == rootDir {
+			return nil
+		}
+		if si.skipHide && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(rootDir, path)
+		if err != nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		items = append(items, IndexFileItem{Path: filepath.ToSlash(rel), Info: info})
+		return nil
+	})
+
+	si.mu.Lock()
+	si.items = items
+	si.mu.Unlock()
+}
+
+func (si *SearchIndex) search(q, mode, scope string) []IndexFileItem {
+	si.mu.RLock()
+	defer si.mu.RUnlock()
+
+	scope = strings.Trim(filepath.ToSlash(scope), "/")
+	var matcher func(name string) bool
+	switch mode {
+	case "regex":
+		re, err := regexp.Compile(q)
+		if err != nil {
+			return nil
+		}
+		matcher = re.MatchString
+	default:
+		ql := strings.ToLower(q)
+		matcher = func(name string) bool { return strings.Contains(strings.ToLower(name), ql) }
+	}
+
+	var out []IndexFileItem
+	for _, it := range si.items {
+		if scope != "" && it.Path != scope && !strings.HasPrefix(it.Path, scope+"/") {
+			continue
+		}
+		if matcher(filepath.Base(it.Path)) {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+var searchIndex *SearchIndex
+
+type searchResult struct {
+	Name    string `json:"name"`
+	RelPath string `json:"relpath"`
+	IsDir   bool   `json:"isDir"`
+	Size    int64  `json:"size"`
+	Mod     string `json:"mod"`
+}
+
+func searchHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		jsonError(w, "q is required", http.StatusBadRequest)
+		return
+	}
+	mode := pick(r.URL.Query().Get("mode"), "substr", "regex")
+	scope := r.URL.Query().Get("path")
+
+	items := searchIndex.search(q, mode, scope)
+	results := make([]searchResult, 0, len(items))
+	for _, it := range items {
+		results = append(results, searchResult{
+			Name:    filepath.Base(it.Path),
+			RelPath: it.Path,
+			IsDir:   it.Info.IsDir(),
+			Size:    it.Info.Size(),
+			Mod:     it.Info.ModTime().Format(time.RFC3339),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestSearchIndexSubstrAndRegex(t *testing.T) {
+	si := &SearchIndex{items: []IndexFileItem{
+		{Path: "foo/bar.txt"},
+		{Path: "foo/baz.go"},
+		{Path: "qux.md"},
+	}}
+
+	t.Run("substr", func(t *testing.T) {
+		got := si.search("ba", "substr", "")
+		if len(got) != 2 {
+			t.Fatalf("want 2 substr matches, got %d: %+v", len(got), got)
+		}
+	})
+
+	t.Run("regex", func(t *testing.T) {
+		got := si.search(`\.go$`, "regex", "")
+		if len(got) != 1 || got[0].Path != "foo/baz.go" {
+			t.Fatalf("want [foo/baz.go], got %+v", got)
+		}
+	})
+
+	t.Run("invalid regex", func(t *testing.T) {
+		if got := si.search("[", "regex", ""); got != nil {
+			t.Fatalf("want nil for an invalid regex, got %+v", got)
+		}
+	})
+
+	t.Run("scoped", func(t *testing.T) {
+		got := si.search("ba", "substr", "foo")
+		if len(got) != 2 {
+			t.Fatalf("want 2 matches scoped to foo/, got %d: %+v", len(got), got)
+		}
+		if got := si.search("qux", "substr", "foo"); len(got) != 0 {
+			t.Fatalf("want 0 matches for qux.md scoped to foo/, got %+v", got)
+		}
+	})
+}
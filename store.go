@@ -0,0 +1,247 @@
+package main
+
+import (
+	"archive/zip"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store abstracts the read path shared by listHandler and downloadHandler
+// so that a browsable root doesn't have to be a plain directory on disk.
+type Store interface {
+	Open(path string) (fs.File, error)
+	ReadDir(path string) ([]fs.DirEntry, error)
+	Stat(path string) (fs.FileInfo, error)
+}
+
+// diskStore serves rootDir directly off the filesystem; this is the
+// original, and still default, behavior.
+type diskStore struct {
+	root string
+}
+
+func newDiskStore(root string) *diskStore {
+	return &diskStore{root: root}
+}
+
+// resolve joins p onto the store root and resolves it to its real,
+// symlink-free path, re-checking containment afterward. A plain join +
+// containment check only catches a path that textually escapes root; a
+// symlinked directory inside root can still point outside it.
+func (s *diskStore) resolve(p string) (string, error) {
+	abs := filepath.Join(s.root, filepath.Clean(string(filepath.Separator)+p))
+	if !isWithin(abs, s.root) {
+		return "", fs.ErrPermission
+	}
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", err
+	}
+	if !isWithin(real, s.root) {
+		return "", fs.ErrPermission
+	}
+	return real, nil
+}
+
+func (s *diskStore) Open(p string) (fs.File, error) {
+	abs, err := s.resolve(p)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(abs)
+}
+
+func (s *diskStore) ReadDir(p string) ([]fs.DirEntry, error) {
+	abs, err := s.resolve(p)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadDir(abs)
+}
+
+func (s *diskStore) Stat(p string) (fs.FileInfo, error) {
+	abs, err := s.resolve(p)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(abs)
+}
+
+// zipStore serves the contents of a single .zip file read-only, so a
+// published archive can be browsed without unpacking it to disk.
+type zipStore struct {
+	mu      sync.RWMutex
+	reader  *zip.ReadCloser
+	byPath  map[string]*zip.File     // file path -> entry, files only
+	dirKids map[string][]fs.DirEntry // dir path ("" for root) -> children
+}
+
+func newZipStore(archivePath string) (*zipStore, error) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	s := &zipStore{
+		reader:  zr,
+		byPath:  make(map[string]*zip.File),
+		dirKids: make(map[string][]fs.DirEntry),
+	}
+	seenDirs := map[string]bool{"": true}
+	var ensureDir func(dir string)
+	ensureDir = func(dir string) {
+		if dir == "" || seenDirs[dir] {
+			return
+		}
+		parent := path.Dir(dir)
+		if parent == "." {
+			parent = ""
+		}
+		ensureDir(parent)
+		seenDirs[dir] = true
+		s.dirKids[parent] = append(s.dirKids[parent], zipDirEntry{name: path.Base(dir)})
+	}
+	for _, f := range zr.File {
+		name := strings.TrimSuffix(path.Clean("/"+f.Name), "")
+		name = strings.TrimPrefix(name, "/")
+		if f.FileInfo().IsDir() {
+			ensureDir(strings.TrimSuffix(name, "/"))
+			continue
+		}
+		dir := path.Dir(name)
+		if dir == "." {
+			dir = ""
+		}
+		ensureDir(dir)
+		s.byPath[name] = f
+		s.dirKids[dir] = append(s.dirKids[dir], zipFileEntry{f: f})
+	}
+	for dir, kids := range s.dirKids {
+		sort.Slice(kids, func(i, j int) bool { return kids[i].Name() < kids[j].Name() })
+		s.dirKids[dir] = kids
+	}
+	return s, nil
+}
+
+func zipNormalize(p string) string {
+	p = path.Clean("/" + filepath.ToSlash(p))
+	return strings.TrimPrefix(p, "/")
+}
+
+func (s *zipStore) Open(p string) (fs.File, error) {
+	p = zipNormalize(p)
+	s.mu.RLock()
+	f, ok := s.byPath[p]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	return zipOpenFile{ReadCloser: rc, info: f.FileInfo()}, nil
+}
+
+// zipOpenFile adapts the io.ReadCloser returned by zip.File.Open into an
+// fs.File by supplying Stat from the already-known directory entry.
+type zipOpenFile struct {
+	io.ReadCloser
+	info fs.FileInfo
+}
+
+func (f zipOpenFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (s *zipStore) ReadDir(p string) ([]fs.DirEntry, error) {
+	p = zipNormalize(p)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	kids, ok := s.dirKids[p]
+	if !ok && p != "" {
+		return nil, fs.ErrNotExist
+	}
+	return kids, nil
+}
+
+func (s *zipStore) Stat(p string) (fs.FileInfo, error) {
+	p = zipNormalize(p)
+	if p == "" {
+		return zipRootInfo{}, nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if f, ok := s.byPath[p]; ok {
+		return f.FileInfo(), nil
+	}
+	if _, ok := s.dirKids[p]; ok {
+		return zipDirInfo{name: path.Base(p)}, nil
+	}
+	return nil, fs.ErrNotExist
+}
+
+type zipFileEntry struct{ f *zip.File }
+
+func (e zipFileEntry) Name() string               { return path.Base(e.f.Name) }
+func (e zipFileEntry) IsDir() bool                { return false }
+func (e zipFileEntry) Type() fs.FileMode          { return e.f.Mode().Type() }
+func (e zipFileEntry) Info() (fs.FileInfo, error) { return e.f.FileInfo(), nil }
+
+type zipDirEntry struct{ name string }
+
+func (e zipDirEntry) Name() string               { return e.name }
+func (e zipDirEntry) IsDir() bool                { return true }
+func (e zipDirEntry) Type() fs.FileMode          { return fs.ModeDir }
+func (e zipDirEntry) Info() (fs.FileInfo, error) { return zipDirInfo{name: e.name}, nil }
+
+// zipDirInfo and zipRootInfo synthesize os.FileInfo for directories that
+// only implicitly exist inside the archive's flat file list.
+type zipDirInfo struct{ name string }
+
+func (i zipDirInfo) Name() string       { return i.name }
+func (i zipDirInfo) Size() int64        { return 0 }
+func (i zipDirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0o555 }
+func (i zipDirInfo) ModTime() time.Time { return time.Time{} }
+func (i zipDirInfo) IsDir() bool        { return true }
+func (i zipDirInfo) Sys() any           { return nil }
+
+type zipRootInfo struct{}
+
+func (zipRootInfo) Name() string       { return "/" }
+func (zipRootInfo) Size() int64        { return 0 }
+func (zipRootInfo) Mode() fs.FileMode  { return fs.ModeDir | 0o555 }
+func (zipRootInfo) ModTime() time.Time { return time.Time{} }
+func (zipRootInfo) IsDir() bool        { return true }
+func (zipRootInfo) Sys() any           { return nil }
+
+func isZipRoot(root string) bool {
+	return strings.EqualFold(filepath.Ext(root), ".zip")
+}
+
+// isDiskBacked reports whether store walks rootDir on the real
+// filesystem. Features that still shell out to os/filepath directly
+// (archiveHandler, SearchIndex) only work against such a store.
+func isDiskBacked() bool {
+	_, ok := store.(*diskStore)
+	return ok
+}
+
+func newStoreFor(root string) Store {
+	if isZipRoot(root) {
+		zs, err := newZipStore(root)
+		if err != nil {
+			log.Fatalf("failed to open zip store %s: %v", root, err)
+		}
+		return zs
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		log.Fatalf("failed to ensure ROOT_DIR: %v", err)
+	}
+	return newDiskStore(root)
+}